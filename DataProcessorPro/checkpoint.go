@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Checkpoint records how many of an input file's data rows a method's sinks
+// have flushed (see reportCommitted), so an interrupted or crashed run can
+// resume instead of re-reading rows already accounted for.
+type Checkpoint struct {
+	Method    string `json:"method"`
+	InputHash string `json:"input_hash"`
+	LastIndex int    `json:"last_index"`
+}
+
+func checkpointPath(method string) string {
+	return method + ".ckpt"
+}
+
+// hashInputFile returns a hex SHA-256 digest of filename's contents, used to
+// confirm a checkpoint still matches the input it was written against.
+func hashInputFile(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// loadCheckpoint returns the data-row index method should resume from (the
+// count of rows its sinks had flushed) against a file hashing to inputHash,
+// or -1 if no valid checkpoint exists (none written yet, or it was written
+// against a different input file).
+func loadCheckpoint(method, inputHash string) int {
+	data, err := os.ReadFile(checkpointPath(method))
+	if err != nil {
+		return -1
+	}
+
+	var ckpt Checkpoint
+	if err := json.Unmarshal(data, &ckpt); err != nil || ckpt.InputHash != inputHash {
+		return -1
+	}
+	return ckpt.LastIndex
+}
+
+// saveCheckpoint persists method's progress against inputHash to disk.
+func saveCheckpoint(method, inputHash string, lastIndex int) {
+	data, err := json.Marshal(Checkpoint{Method: method, InputHash: inputHash, LastIndex: lastIndex})
+	if err != nil {
+		fmt.Printf("Warning: could not encode checkpoint for %s: %v\n", method, err)
+		return
+	}
+	if err := os.WriteFile(checkpointPath(method), data, 0644); err != nil {
+		fmt.Printf("Warning: could not write checkpoint for %s: %v\n", method, err)
+	}
+}
+
+// clearCheckpoint removes method's checkpoint file once a run completes in
+// full, so the next invocation starts fresh rather than "resuming" from the
+// end of a finished run.
+func clearCheckpoint(method string) {
+	os.Remove(checkpointPath(method))
+}
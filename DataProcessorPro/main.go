@@ -1,18 +1,23 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
+	"flag"
 	"fmt"
-	"math/rand"
+	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/schollz/progressbar/v3"
 	"github.com/xuri/excelize/v2"
+
+	"github.com/XenosWarlocks/Read-Write-Analysis/DataProcessorPro/sinks"
 )
 
 // Configuration struct for processing options
@@ -21,332 +26,823 @@ type ProcessConfig struct {
 	NumWorkers   int
 	UseBuffering bool
 	Method       string // "sequential", "concurrent", "batch"
+
+	// InputChannelCap bounds how many rows the producer may read ahead of the
+	// classifier workers, keeping memory use flat regardless of sheet size.
+	InputChannelCap int
+	// OutputChannelCap bounds how many classified rows may queue up waiting
+	// for their writer goroutine.
+	OutputChannelCap int
+	// FlushEvery controls how often each output sink is flushed to disk, in
+	// number of rows. The sharded concurrent path does not use it: its shard
+	// files are flushed only when closed.
+	FlushEvery int
+
+	// OutputFormat selects a registered sinks.Sink implementation ("csv",
+	// "ndjson", "parquet").
+	OutputFormat string
+	// GzipOutput wraps the chosen OutputFormat in gzip compression, where a
+	// gzip variant is registered for that format.
+	GzipOutput bool
+
+	// Splitter decides which rows land in VA vs VB. All three methods call
+	// the same Splitter so their outputs are directly comparable.
+	Splitter Splitter
+
+	// EnableProfiling turns on per-method CPU and heap profiling, written
+	// under ProfileDir.
+	EnableProfiling bool
+	// ProfileDir is where cpu_<method>.pprof and heap_<method>.pprof are
+	// written when EnableProfiling is set.
+	ProfileDir string
+
+	// ShardedOutput routes the concurrent method through per-worker shard
+	// files (merged into the final VA/VB files once all workers finish)
+	// instead of a single writer goroutine per destination, trading a merge
+	// pass for less writer-goroutine contention at very high worker counts.
+	// Not supported for the "parquet" format, whose shard files cannot be
+	// concatenated at the byte level.
+	ShardedOutput bool
 }
 
 // Metrics for performance tracking
 type PerformanceMetrics struct {
-	StartTime   time.Time
-	EndTime     time.Time
-	RowsHandled int
-	MemoryUsed  uint64
-	Method      string
+	StartTime time.Time
+	EndTime   time.Time
+	// RowsHandled is updated atomically from writer goroutines as each row is
+	// written to its sink, so it reflects rows handed to a sink rather than
+	// rows queued; a row counted here may still sit in the sink's buffer.
+	RowsHandled int64
+	// RowsFlushed is updated atomically from writer goroutines each time a
+	// sink durably flushes rows, and backs the checkpoint saved by
+	// reportCommitted so resuming a run never skips a row that wasn't
+	// actually written to disk.
+	RowsFlushed int64
+	// MemoryUsed holds the peak heap allocation observed while this method
+	// ran, sampled periodically rather than read once at the end.
+	MemoryUsed uint64
+	Method     string
+
+	// AllocsDelta is bytes allocated during this method (MemStats.TotalAlloc
+	// after minus before), independent of how much survived GC.
+	AllocsDelta uint64
+	// GCCount is how many garbage collections ran during this method.
+	GCCount uint32
+	// GCPauseNs is total GC stop-the-world pause time during this method.
+	GCPauseNs uint64
 }
 
 func (pm *PerformanceMetrics) Duration() time.Duration {
 	return pm.EndTime.Sub(pm.StartTime)
 }
 
+// samplePeakMemory polls runtime.MemStats every interval and keeps the
+// highest Alloc value it has seen in metrics.MemoryUsed until stop is closed.
+func samplePeakMemory(metrics *PerformanceMetrics, interval time.Duration, stop <-chan struct{}) {
+	var m runtime.MemStats
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			runtime.ReadMemStats(&m)
+			for {
+				peak := atomic.LoadUint64(&metrics.MemoryUsed)
+				if m.Alloc <= peak {
+					break
+				}
+				if atomic.CompareAndSwapUint64(&metrics.MemoryUsed, peak, m.Alloc) {
+					break
+				}
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
 // Educational wrapper to demonstrate different processing methods
-func demonstrateProcessingMethods(inputFile string) {
+func demonstrateProcessingMethods(ctx context.Context, inputFile, outputFormat, splitSpec string, gzipOutput, enableProfiling bool) {
 	fmt.Println("🎓 Educational Data Processing Algorithm Demo")
+	fmt.Printf("   Output format: %s%s\n", outputFormat, map[bool]string{true: " (gzip)"}[gzipOutput])
 	fmt.Println("============================================")
 
-	// Load data once
-	data := loadExcelData(inputFile)
-
-	// Run and measure different methods
+	// Run and measure different methods. Each method streams the workbook
+	// from disk itself, so nothing here holds the whole sheet in memory.
 	methods := []ProcessConfig{
-		{Method: "sequential", BatchSize: 1, NumWorkers: 1, UseBuffering: false},
-		{Method: "concurrent", BatchSize: 1, NumWorkers: runtime.NumCPU(), UseBuffering: true},
-		{Method: "batch", BatchSize: 1000, NumWorkers: runtime.NumCPU(), UseBuffering: true},
+		{Method: "sequential", BatchSize: 1, NumWorkers: 1, UseBuffering: false, InputChannelCap: 256, OutputChannelCap: 256, FlushEvery: 500},
+		{Method: "concurrent", BatchSize: 1, NumWorkers: runtime.NumCPU(), UseBuffering: true, InputChannelCap: 1024, OutputChannelCap: 1024, FlushEvery: 500},
+		{Method: "batch", BatchSize: 1000, NumWorkers: runtime.NumCPU(), UseBuffering: true, InputChannelCap: 1024, OutputChannelCap: 1024, FlushEvery: 500},
 	}
 
 	var results []PerformanceMetrics
 
-	for _, config := range methods {
+	// Every method gets its own Splitter instance built fresh from the same
+	// spec and seed, so they all draw the same VA/VB boundary (or, for a
+	// stateful strategy like bernoulli/stratified, the same sequence of
+	// decisions) rather than each running a fresh coin flip or carrying
+	// state left over from the previous method's run.
+	totalRows := countDataRows(inputFile)
+
+	for i := range methods {
+		methods[i].OutputFormat = outputFormat
+		methods[i].GzipOutput = gzipOutput
+		splitter, err := NewSplitterFromSpec(splitSpec, totalRows, 99)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		methods[i].Splitter = splitter
+		methods[i].EnableProfiling = enableProfiling
+		methods[i].ProfileDir = "output"
+		config := methods[i]
+
 		fmt.Printf("\n📊 Testing %s method...\n", config.Method)
-		metric := processData(data, config)
+		metric := processData(ctx, inputFile, config)
 		results = append(results, metric)
 
+		if ctx.Err() != nil {
+			fmt.Printf("\n⚠️  %s method interrupted, stopping before the remaining methods run.\n", config.Method)
+			break
+		}
+
 		// Clear previous output files
-		os.Remove("VA_" + config.Method + ".csv")
-		os.Remove("VB_" + config.Method + ".csv")
+		ext := sinks.Ext(outputFormat, gzipOutput)
+		os.Remove("VA_" + config.Method + ext)
+		os.Remove("VB_" + config.Method + ext)
 	}
 
 	// Display comparative results
 	displayResults(results)
 }
 
-func processData(data [][]string, config ProcessConfig) PerformanceMetrics {
-	metrics := PerformanceMetrics{
+func processData(ctx context.Context, inputFile string, config ProcessConfig) PerformanceMetrics {
+	metrics := &PerformanceMetrics{
 		StartTime: time.Now(),
 		Method:    config.Method,
 	}
 
+	stop := make(chan struct{})
+	samplerDone := make(chan struct{})
+	go func() {
+		samplePeakMemory(metrics, 50*time.Millisecond, stop)
+		close(samplerDone)
+	}()
+
+	if config.EnableProfiling {
+		stopCPUProfile, err := startCPUProfile(config.Method, config.ProfileDir)
+		if err != nil {
+			fmt.Printf("Warning: could not start CPU profile for %s: %v\n", config.Method, err)
+		} else {
+			defer stopCPUProfile()
+		}
+	}
+
+	before := captureGCSnapshot()
+
+	// Resume from a prior checkpoint if one exists and still matches
+	// inputFile, so an interrupted run doesn't have to re-read rows it
+	// already accounted for.
+	inputHash, err := hashInputFile(inputFile)
+	if err != nil {
+		fmt.Printf("Warning: could not hash %s for checkpointing: %v\n", inputFile, err)
+	}
+	resumeIndex := loadCheckpoint(config.Method, inputHash)
+	switch {
+	case resumeIndex < 0:
+		resumeIndex = 0
+	case resumeIndex > 0 && !sinks.SupportsAppend(config.OutputFormat, config.GzipOutput):
+		// Mirrors the reset processConcurrentSharded already does for its
+		// own non-resumable shard files: a format with no OpenAppend entry
+		// (parquet) can't have committed rows safely reopened for appending,
+		// so honoring this checkpoint would silently drop every row from
+		// here on instead of resuming them.
+		fmt.Printf("   - %s output has no append support, restarting %s from row 0\n", config.OutputFormat, config.Method)
+		resumeIndex = 0
+	default:
+		fmt.Printf("   - Resuming %s from checkpoint at row %d\n", config.Method, resumeIndex)
+	}
+
 	switch config.Method {
 	case "sequential":
-		processSequential(data)
+		processSequential(ctx, inputFile, config, metrics, resumeIndex, inputHash)
 	case "concurrent":
-		processConcurrent(data, config.NumWorkers)
+		processConcurrent(ctx, inputFile, config, metrics, resumeIndex, inputHash)
 	case "batch":
-		processBatch(data, config.BatchSize, config.NumWorkers)
+		processBatch(ctx, inputFile, config, metrics, resumeIndex, inputHash)
+	}
+
+	if ctx.Err() == nil {
+		clearCheckpoint(config.Method)
+	}
+
+	after := captureGCSnapshot()
+	metrics.AllocsDelta = after.TotalAlloc - before.TotalAlloc
+	metrics.GCCount = after.NumGC - before.NumGC
+	metrics.GCPauseNs = after.PauseTotalNs - before.PauseTotalNs
+
+	if config.EnableProfiling {
+		if err := writeHeapProfile(config.Method, config.ProfileDir); err != nil {
+			fmt.Printf("Warning: could not write heap profile for %s: %v\n", config.Method, err)
+		}
 	}
 
+	// Wait for the sampler to actually observe stop's close before reading
+	// metrics.MemoryUsed below: closing stop alone doesn't guarantee the
+	// sampler isn't still mid-CompareAndSwapUint64 on it.
+	close(stop)
+	<-samplerDone
 	metrics.EndTime = time.Now()
-	metrics.RowsHandled = len(data)
 
-	var m runtime.MemStats
-	runtime.ReadMemStats(&m)
-	metrics.MemoryUsed = m.Alloc
+	return *metrics
+}
 
-	return metrics
+// countDataRows counts the sheet's data rows (the header excluded) by
+// streaming through it with the same row iterator streamRows uses, so at
+// most one row is held in memory at a time regardless of sheet size.
+// Splitters that need a row count up front (HalfSplit) use this.
+//
+// This deliberately does not trust excelize.GetSheetDimension: a sheet's
+// <dimension> attribute is frequently stale or just "A1" for perfectly
+// ordinary files written without excelize's streaming writer, which would
+// silently misreport the row count rather than fail loudly.
+func countDataRows(inputFile string) int {
+	xlsx, err := excelize.OpenFile(inputFile)
+	if err != nil {
+		panic(err)
+	}
+	defer xlsx.Close()
+
+	sheet := xlsx.GetSheetList()[0]
+	rows, err := xlsx.Rows(sheet)
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	total := 0
+	skippedHeader := false
+	for rows.Next() {
+		if !skippedHeader {
+			skippedHeader = true
+			continue
+		}
+		total++
+	}
+	return total
+}
+
+// streamRows opens inputFile and pushes every data row (the header is
+// skipped) onto rowsCh using excelize's row iterator, so at most one row is
+// held in memory at a time regardless of sheet size. startIndex skips that
+// many data rows without emitting them, for resuming from a checkpoint. ctx
+// cancellation stops the producer early; closing rowsCh cascades that
+// shutdown through the classifier and writer goroutines downstream.
+//
+// streamRows itself doesn't checkpoint: it only knows how far it has read,
+// not how far the writers downstream have actually committed to disk, and
+// checkpointing read-ahead progress is exactly what let a resumed run skip
+// rows that were never durably written. See reportCommitted.
+func streamRows(ctx context.Context, inputFile string, rowsCh chan<- []string, startIndex int) {
+	defer close(rowsCh)
+
+	xlsx, err := excelize.OpenFile(inputFile)
+	if err != nil {
+		panic(err)
+	}
+	defer xlsx.Close()
+
+	sheet := xlsx.GetSheetList()[0]
+	rows, err := xlsx.Rows(sheet)
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	skippedHeader := false
+	index := 0
+	for rows.Next() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		row, err := rows.Columns()
+		if err != nil {
+			panic(err)
+		}
+		if !skippedHeader {
+			skippedHeader = true
+			continue
+		}
+
+		if index < startIndex {
+			index++
+			continue
+		}
+
+		select {
+		case rowsCh <- row:
+		case <-ctx.Done():
+			return
+		}
+
+		index++
+	}
+}
+
+// writeSink owns baseName's sinks.Sink exclusively for its lifetime,
+// draining rowsCh until it is closed. Centralizing writes in one goroutine
+// per destination file avoids interleaving records under concurrent
+// producers, regardless of which output format is in play. When resuming is
+// true the sink appends to baseName's existing file instead of truncating
+// it, so rows written before an earlier interruption are preserved.
+//
+// resumeIndex and inputHash are threaded through only to feed
+// reportCommitted: every flush, writeSink reports how many more rows it has
+// just made durable, so the checkpoint tracks committed progress rather than
+// the producer's read-ahead position.
+func writeSink(baseName string, config ProcessConfig, resuming bool, rowsCh <-chan []string, bar *progressbar.ProgressBar, metrics *PerformanceMetrics, wg *sync.WaitGroup, resumeIndex int, inputHash string) {
+	defer wg.Done()
+
+	filename := baseName + sinks.Ext(config.OutputFormat, config.GzipOutput)
+	open := sinks.Open
+	if resuming {
+		open = sinks.OpenAppend
+	}
+	sink, err := open(config.OutputFormat, filename, config.GzipOutput)
+	if err != nil {
+		fmt.Printf("Error opening sink %s: %v\n", filename, err)
+		// Drain rowsCh instead of returning immediately: the classifier and
+		// producer goroutines upstream send to it unconditionally, and with
+		// nobody reading, they'd block forever once its buffer fills.
+		for range rowsCh {
+		}
+		return
+	}
+	defer sink.Close()
+
+	flushEvery := config.FlushEvery
+	if flushEvery <= 0 {
+		flushEvery = 500
+	}
+
+	written := 0
+	sinceFlush := 0
+	for row := range rowsCh {
+		if err := sink.WriteRow(row); err != nil {
+			fmt.Printf("Error writing to %s: %v\n", filename, err)
+			continue
+		}
+		atomic.AddInt64(&metrics.RowsHandled, 1)
+		if bar != nil {
+			bar.Add(1)
+		}
+
+		written++
+		sinceFlush++
+		if sinceFlush >= flushEvery {
+			sink.Flush()
+			reportCommitted(metrics, config.Method, inputHash, resumeIndex, sinceFlush)
+			sinceFlush = 0
+		}
+	}
+
+	// Rows buffered since the last periodic flush still need to land on disk
+	// and be accounted for, whether rowsCh closed because the method
+	// finished normally or because an upstream cancellation cascaded down to
+	// it: otherwise a graceful SIGINT leaves these rows durable but
+	// uncheckpointed, and OpenAppend would rewrite duplicates of them on the
+	// next run.
+	if sinceFlush > 0 {
+		sink.Flush()
+		reportCommitted(metrics, config.Method, inputHash, resumeIndex, sinceFlush)
+	}
+}
+
+// reportCommitted accumulates delta newly-flushed rows from one sink into
+// metrics.RowsFlushed (shared with its sibling VA/VB sink) and saves a
+// checkpoint reflecting resumeIndex plus every row flushed by either sink so
+// far.
+//
+// This is an approximation, not an exact "last row index committed to each
+// sink": it doesn't track which original row indices landed in which sink,
+// so if one sink runs well ahead of the other a resumed run could in theory
+// re-flush a handful of rows the faster sink already committed. It closes
+// the much larger gap this was written to fix — a checkpoint based on the
+// producer's read-ahead position, which could sit far past what either sink
+// had actually made durable.
+func reportCommitted(metrics *PerformanceMetrics, method, inputHash string, resumeIndex, delta int) {
+	total := atomic.AddInt64(&metrics.RowsFlushed, int64(delta))
+	saveCheckpoint(method, inputHash, resumeIndex+int(total))
 }
 
 // Sequential Processing Method
-func processSequential(data [][]string) {
+func processSequential(ctx context.Context, inputFile string, config ProcessConfig, metrics *PerformanceMetrics, resumeIndex int, inputHash string) {
 	fmt.Println("🔄 Sequential Processing")
 	fmt.Println("   - Single thread")
 	fmt.Println("   - No buffering")
 	fmt.Println("   - Simple but slower for large datasets")
 
-	r := rand.New(rand.NewSource(99))
-	totalRows := len(data)
-	splitPoint := totalRows / 2
+	resuming := resumeIndex > 0
 
-	if totalRows%2 != 0 && r.Float32() < 0.5 {
-		splitPoint++
+	rowsCh := make(chan []string, config.InputChannelCap)
+	go streamRows(ctx, inputFile, rowsCh, resumeIndex)
+
+	outA := make(chan []string, config.OutputChannelCap)
+	outB := make(chan []string, config.OutputChannelCap)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go writeSink("VA_sequential", config, resuming, outA, nil, metrics, &wg, resumeIndex, inputHash)
+	go writeSink("VB_sequential", config, resuming, outB, nil, metrics, &wg, resumeIndex, inputHash)
+
+	index := resumeIndex
+	for row := range rowsCh {
+		dest := outB
+		if config.Splitter.Assign(index, row) {
+			dest = outA
+		}
+		// Select on ctx here too, so a cancellation stops this loop right
+		// away instead of waiting for room in a full dest.
+		select {
+		case dest <- row:
+		case <-ctx.Done():
+			close(outA)
+			close(outB)
+			wg.Wait()
+			return
+		}
+		index++
 	}
+	close(outA)
+	close(outB)
 
-	writeCSVWithProgress("VA_sequential.csv", data[:splitPoint], "Sequential VA")
-	writeCSVWithProgress("VB_sequential.csv", data[splitPoint:], "Sequential VB")
+	wg.Wait()
 }
 
 // Concurrent Processing Method
-func processConcurrent(data [][]string, numWorkers int) {
+func processConcurrent(ctx context.Context, inputFile string, config ProcessConfig, metrics *PerformanceMetrics, resumeIndex int, inputHash string) {
 	fmt.Println("⚡ Concurrent Processing")
 	fmt.Println("   - Multiple goroutines")
 	fmt.Println("   - Buffered channels")
 	fmt.Println("   - Better for I/O-bound tasks")
-	fmt.Printf("   - Using %d workers\n", numWorkers)
-
-	r := rand.New(rand.NewSource(99))
-	totalRows := len(data)
-	splitPoint := totalRows / 2
-
-	if totalRows%2 != 0 && r.Float32() < 0.5 {
-		splitPoint++
+	fmt.Printf("   - Using %d workers\n", config.NumWorkers)
+
+	if config.ShardedOutput {
+		if config.OutputFormat == "parquet" {
+			fmt.Println("   - ShardedOutput is not supported for parquet, falling back to the single-writer path")
+		} else {
+			processConcurrentSharded(ctx, inputFile, config, metrics, resumeIndex, inputHash)
+			return
+		}
 	}
 
-	var wg sync.WaitGroup
+	resuming := resumeIndex > 0
 
-	// Create buffered channels for work distribution
-	resultsA := make(chan []string, numWorkers)
-	resultsB := make(chan []string, numWorkers)
+	type indexedRow struct {
+		index int
+		row   []string
+	}
 
-	// Start worker pools for both files
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(2)
+	rowsCh := make(chan []string, config.InputChannelCap)
+	go streamRows(ctx, inputFile, rowsCh, resumeIndex)
 
-		// Workers for VA file
-		go func(workerID int) {
-			defer wg.Done()
-			fileA, err := os.OpenFile("VA_concurrent.csv", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-			if err != nil {
-				fmt.Printf("Worker %d VA error: %v\n", workerID, err)
+	indexedCh := make(chan indexedRow, config.InputChannelCap)
+	go func() {
+		defer close(indexedCh)
+		i := resumeIndex
+		for row := range rowsCh {
+			select {
+			case indexedCh <- indexedRow{index: i, row: row}:
+			case <-ctx.Done():
 				return
 			}
-			defer fileA.Close()
+			i++
+		}
+	}()
 
-			writerA := csv.NewWriter(fileA)
-			defer writerA.Flush()
+	outA := make(chan []string, config.OutputChannelCap)
+	outB := make(chan []string, config.OutputChannelCap)
+
+	// Classifier workers: read indexed rows, route to the right output
+	// channel via the shared Splitter. Exactly one writer goroutine per
+	// destination file owns that file's sinks.Sink, so classification fan-out
+	// never touches disk directly. Each worker also bails out as soon as
+	// ctx is cancelled, rather than waiting for indexedCh to drain and close.
+	numClassifiers := config.NumWorkers
+	if config.Splitter.OrderSensitive() && numClassifiers != 1 {
+		fmt.Println("   - Splitter is order-sensitive: classifying with a single worker so Assign is called in row order")
+		numClassifiers = 1
+	}
 
-			for row := range resultsA {
-				if err := writerA.Write(row); err != nil {
-					fmt.Printf("Worker %d VA write error: %v\n", workerID, err)
+	var classifiers sync.WaitGroup
+	for i := 0; i < numClassifiers; i++ {
+		classifiers.Add(1)
+		go func() {
+			defer classifiers.Done()
+			for {
+				select {
+				case ir, ok := <-indexedCh:
+					if !ok {
+						return
+					}
+					dest := outB
+					if config.Splitter.Assign(ir.index, ir.row) {
+						dest = outA
+					}
+					select {
+					case dest <- ir.row:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
 				}
 			}
-		}(i)
+		}()
+	}
+
+	go func() {
+		classifiers.Wait()
+		close(outA)
+		close(outB)
+	}()
+
+	barA := progressbar.Default(-1, "Concurrent VA")
+	barB := progressbar.Default(-1, "Concurrent VB")
+
+	var writers sync.WaitGroup
+	writers.Add(2)
+	go writeSink("VA_concurrent", config, resuming, outA, barA, metrics, &writers, resumeIndex, inputHash)
+	go writeSink("VB_concurrent", config, resuming, outB, barB, metrics, &writers, resumeIndex, inputHash)
+
+	writers.Wait()
+}
+
+// processConcurrentSharded is the high-throughput alternative to
+// processConcurrent's fan-in writers: each worker owns a private VA/VB
+// shard file, so there is zero coordination between workers while
+// classifying. Once every worker has drained indexedCh, the shards are
+// concatenated into the final VA_concurrent/VB_concurrent files in shard
+// order and the shard files are removed.
+func processConcurrentSharded(ctx context.Context, inputFile string, config ProcessConfig, metrics *PerformanceMetrics, resumeIndex int, inputHash string) {
+	fmt.Println("   - Sharded output: one shard file per worker, merged at the end")
+
+	// Shard files are transient and removed once merged, so there is
+	// nothing to append to on resume; sharded runs always restart from the
+	// beginning of the input.
+	if resumeIndex > 0 {
+		fmt.Println("   - ShardedOutput has no resumable state, restarting from row 0")
+		resumeIndex = 0
+	}
+
+	type indexedRow struct {
+		index int
+		row   []string
+	}
+
+	rowsCh := make(chan []string, config.InputChannelCap)
+	go streamRows(ctx, inputFile, rowsCh, resumeIndex)
+
+	indexedCh := make(chan indexedRow, config.InputChannelCap)
+	go func() {
+		defer close(indexedCh)
+		i := 0
+		for row := range rowsCh {
+			select {
+			case indexedCh <- indexedRow{index: i, row: row}:
+			case <-ctx.Done():
+				return
+			}
+			i++
+		}
+	}()
+
+	numWorkers := config.NumWorkers
+	if config.Splitter.OrderSensitive() && numWorkers != 1 {
+		fmt.Println("   - Splitter is order-sensitive: sharding with a single worker so Assign is called in row order")
+		numWorkers = 1
+	}
 
-		// Workers for VB file
+	ext := sinks.Ext(config.OutputFormat, config.GzipOutput)
+	shardBasesA := make([]string, numWorkers)
+	shardBasesB := make([]string, numWorkers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		shardBasesA[i] = fmt.Sprintf("VA_concurrent.shard-%d", i)
+		shardBasesB[i] = fmt.Sprintf("VB_concurrent.shard-%d", i)
+
+		wg.Add(1)
 		go func(workerID int) {
 			defer wg.Done()
-			fileB, err := os.OpenFile("VB_concurrent.csv", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+			sinkA, err := sinks.Open(config.OutputFormat, shardBasesA[workerID]+ext, config.GzipOutput)
 			if err != nil {
-				fmt.Printf("Worker %d VB error: %v\n", workerID, err)
+				fmt.Printf("Worker %d: error opening VA shard: %v\n", workerID, err)
 				return
 			}
-			defer fileB.Close()
+			defer sinkA.Close()
 
-			writerB := csv.NewWriter(fileB)
-			defer writerB.Flush()
-
-			for row := range resultsB {
-				if err := writerB.Write(row); err != nil {
-					fmt.Printf("Worker %d VB write error: %v\n", workerID, err)
+			sinkB, err := sinks.Open(config.OutputFormat, shardBasesB[workerID]+ext, config.GzipOutput)
+			if err != nil {
+				fmt.Printf("Worker %d: error opening VB shard: %v\n", workerID, err)
+				return
+			}
+			defer sinkB.Close()
+
+			for {
+				select {
+				case ir, ok := <-indexedCh:
+					if !ok {
+						return
+					}
+					sink := sinkB
+					if config.Splitter.Assign(ir.index, ir.row) {
+						sink = sinkA
+					}
+					if err := sink.WriteRow(ir.row); err != nil {
+						fmt.Printf("Worker %d: write error: %v\n", workerID, err)
+						continue
+					}
+					atomic.AddInt64(&metrics.RowsHandled, 1)
+				case <-ctx.Done():
+					return
 				}
 			}
 		}(i)
 	}
+	wg.Wait()
 
-	// Create progress bars
-	barA := progressbar.Default(int64(splitPoint), "Concurrent VA")
-	barB := progressbar.Default(int64(totalRows-splitPoint), "Concurrent VB")
+	if ctx.Err() != nil {
+		fmt.Println("   - Cancelled before all shards finished; skipping merge to avoid a partial file")
+		return
+	}
 
-	// Distribute data to appropriate channels
-	go func() {
-		for i, row := range data[:splitPoint] {
-			resultsA <- row
-			barA.Add(1)
-			if i%100 == 0 { // Add some artificial delay to demonstrate concurrent processing
-				time.Sleep(time.Microsecond)
-			}
-		}
-		close(resultsA)
-	}()
+	if err := mergeShards("VA_concurrent"+ext, shardBasesA, ext); err != nil {
+		fmt.Printf("Error merging VA shards: %v\n", err)
+	}
+	if err := mergeShards("VB_concurrent"+ext, shardBasesB, ext); err != nil {
+		fmt.Printf("Error merging VB shards: %v\n", err)
+	}
+}
 
-	go func() {
-		for i, row := range data[splitPoint:] {
-			resultsB <- row
-			barB.Add(1)
-			if i%100 == 0 { // Add some artificial delay to demonstrate concurrent processing
-				time.Sleep(time.Microsecond)
+// mergeShards concatenates each shard's file, in order, into destPath, then
+// removes the shard files. This only produces a valid file for line-oriented
+// and gzip-member-oriented formats (csv, csv.gz, ndjson, ndjson.gz). A
+// worker creates both of its shard files before reading any row, so a
+// shard file only goes missing when its worker failed to open it; such a
+// shard is skipped.
+func mergeShards(destPath string, shardBases []string, ext string) error {
+	dest, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer dest.Close()
+
+	for _, base := range shardBases {
+		shardPath := base + ext
+		src, err := os.Open(shardPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
 			}
+			return err
 		}
-		close(resultsB)
-	}()
 
-	wg.Wait()
+		_, copyErr := io.Copy(dest, src)
+		src.Close()
+		if copyErr != nil {
+			return copyErr
+		}
+		os.Remove(shardPath)
+	}
+	return nil
 }
 
 // Batch Processing Method
-func processBatch(data [][]string, batchSize, numWorkers int) {
+func processBatch(ctx context.Context, inputFile string, config ProcessConfig, metrics *PerformanceMetrics, resumeIndex int, inputHash string) {
 	fmt.Println("📦 Batch Processing")
 	fmt.Println("   - Processing in chunks")
 	fmt.Println("   - Worker pool pattern")
 	fmt.Println("   - Best for large datasets")
 
-	r := rand.New(rand.NewSource(99))
-	totalRows := len(data)
-	splitPoint := totalRows / 2
+	resuming := resumeIndex > 0
 
-	if totalRows%2 != 0 && r.Float32() < 0.5 {
-		splitPoint++
-	}
+	rowsCh := make(chan []string, config.InputChannelCap)
+	go streamRows(ctx, inputFile, rowsCh, resumeIndex)
 
-	// Create worker pools
-	var wg sync.WaitGroup
-	jobs := make(chan [][]string, numWorkers)
-	results := make(chan [][]string, numWorkers)
-
-	// Start worker pool
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go batchWorker(jobs, results, &wg)
+	// Group the streamed rows into batches of config.BatchSize before
+	// handing them to the worker pool, preserving the original row index of
+	// each row so classification stays consistent with the other methods.
+	type batch struct {
+		startIndex int
+		rows       [][]string
 	}
-
-	// Split data into batches and send to workers
+	batchesCh := make(chan batch, config.NumWorkers)
 	go func() {
-		for i := 0; i < len(data); i += batchSize {
-			end := i + batchSize
-			if end > len(data) {
-				end = len(data)
+		defer close(batchesCh)
+		current := batch{}
+		index := resumeIndex
+		for row := range rowsCh {
+			if len(current.rows) == 0 {
+				current.startIndex = index
+			}
+			current.rows = append(current.rows, row)
+			index++
+			if len(current.rows) >= config.BatchSize {
+				select {
+				case batchesCh <- current:
+				case <-ctx.Done():
+					return
+				}
+				current = batch{}
+			}
+		}
+		if len(current.rows) > 0 {
+			select {
+			case batchesCh <- current:
+			case <-ctx.Done():
 			}
-			jobs <- data[i:end]
 		}
-		close(jobs)
 	}()
 
-	// Collect and write results
-	writeCSVBatched("VA_batch.csv", "VB_batch.csv", results, totalRows, "Batch Processing")
-	wg.Wait()
-}
-
-func batchWorker(jobs <-chan [][]string, results chan<- [][]string, wg *sync.WaitGroup) {
-	defer wg.Done()
-	for batch := range jobs {
-		// Process batch
-		results <- batch
-	}
-}
+	outA := make(chan []string, config.OutputChannelCap)
+	outB := make(chan []string, config.OutputChannelCap)
 
-// Helper functions
-func loadExcelData(filename string) [][]string {
-	xlsx, err := excelize.OpenFile(filename)
-	if err != nil {
-		panic(err)
+	numWorkers := config.NumWorkers
+	if config.Splitter.OrderSensitive() && numWorkers != 1 {
+		fmt.Println("   - Splitter is order-sensitive: processing batches with a single worker so Assign is called in row order")
+		numWorkers = 1
 	}
-	defer xlsx.Close()
 
-	rows, err := xlsx.GetRows(xlsx.GetSheetList()[0])
-	if err != nil {
-		panic(err)
+	var wg sync.WaitGroup
+	for i := 0; i < numWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case b, ok := <-batchesCh:
+					if !ok {
+						return
+					}
+					for offset, row := range b.rows {
+						dest := outB
+						if config.Splitter.Assign(b.startIndex+offset, row) {
+							dest = outA
+						}
+						select {
+						case dest <- row:
+						case <-ctx.Done():
+							return
+						}
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
 	}
-	return rows[1:] // Skip header
-}
 
-func writeCSVWithProgress(filename string, data [][]string, label string) {
-	file, _ := os.Create(filename)
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	go func() {
+		wg.Wait()
+		close(outA)
+		close(outB)
+	}()
 
-	bar := progressbar.Default(int64(len(data)), label)
+	bar := progressbar.Default(-1, "Batch Processing")
 
-	for _, row := range data {
-		writer.Write(row)
-		bar.Add(1)
-	}
-}
+	var writers sync.WaitGroup
+	writers.Add(2)
+	go writeSink("VA_batch", config, resuming, outA, bar, metrics, &writers, resumeIndex, inputHash)
+	go writeSink("VB_batch", config, resuming, outB, bar, metrics, &writers, resumeIndex, inputHash)
 
-// func writeCSVBuffered(filename string, data [][]string, results chan []string, label string) {
-// 	file, _ := os.Create(filename)
-// 	defer file.Close()
-
-// 	writer := csv.NewWriter(file)
-// 	defer writer.Flush()
-
-// 	bar := progressbar.Default(int64(len(data)), label)
-
-// 	for _, row := range data {
-// 		writer.Write(row)
-// 		results <- row
-// 		bar.Add(1)
-// 	}
-// }
-
-func writeCSVBatched(filenameA, filenameB string, results chan [][]string, totalRows int, label string) {
-	fileA, _ := os.Create(filenameA)
-	fileB, _ := os.Create(filenameB)
-	defer fileA.Close()
-	defer fileB.Close()
-
-	writerA := csv.NewWriter(fileA)
-	writerB := csv.NewWriter(fileB)
-	defer writerA.Flush()
-	defer writerB.Flush()
-
-	bar := progressbar.Default(int64(totalRows), label)
-
-	for batch := range results {
-		for _, row := range batch {
-			if rand.Float32() < 0.5 {
-				writerA.Write(row)
-			} else {
-				writerB.Write(row)
-			}
-			bar.Add(1)
-		}
-	}
+	writers.Wait()
 }
 
 func displayResults(metrics []PerformanceMetrics) {
 	fmt.Println("\n📈 Performance Comparison")
 	fmt.Println("========================")
 
-	fmt.Printf("%-12s | %-10s | %-15s | %-10s\n",
-		"Method", "Duration", "Rows/Second", "Memory Used")
-	fmt.Println("------------------------------------------------")
+	fmt.Printf("%-12s | %-10s | %-15s | %-12s | %-6s | %-12s | %-10s\n",
+		"Method", "Duration", "Rows/Second", "Peak Heap", "GCs", "Total Allocs", "GC Pause")
+	fmt.Println("--------------------------------------------------------------------------------------")
 
 	for _, m := range metrics {
 		rowsPerSec := float64(m.RowsHandled) / m.Duration().Seconds()
-		memoryMB := float64(m.MemoryUsed) / 1024 / 1024
+		peakHeapMB := float64(m.MemoryUsed) / 1024 / 1024
+		totalAllocsMB := float64(m.AllocsDelta) / 1024 / 1024
 
-		fmt.Printf("%-12s | %-10s | %-15.2f | %-10.2f MB\n",
+		fmt.Printf("%-12s | %-10s | %-15.2f | %-9.2f MB | %-6d | %-9.2f MB | %-10s\n",
 			m.Method,
 			m.Duration().Round(time.Millisecond),
 			rowsPerSec,
-			memoryMB)
+			peakHeapMB,
+			m.GCCount,
+			totalAllocsMB,
+			time.Duration(m.GCPauseNs).Round(time.Microsecond))
 	}
 }
 
@@ -357,16 +853,25 @@ func main() {
 	// Strip .exe extension if present for help message formatting
 	displayName := strings.TrimSuffix(progName, ".exe")
 
-	if len(os.Args) != 2 {
-		fmt.Printf("Usage: go run %s <sequential_output.xlsx>\n", displayName)
+	outputFormat := flag.String("o", "csv", "output sink format: csv, ndjson, or parquet")
+	gzipOutput := flag.Bool("z", false, "gzip-compress sink output (csv and ndjson only)")
+	enableProfiling := flag.Bool("profile", false, "write cpu_<method>.pprof and heap_<method>.pprof under output/")
+	splitStrategy := flag.String("split", "half", "row-splitting strategy: half, bernoulli:p[:seed], hashmod:col:n, stratified:col")
+	flag.Usage = func() {
+		fmt.Printf("Usage: go run %s [-o format] [-z] [-profile] [-split strategy] <sequential_output.xlsx>\n", displayName)
 		fmt.Println("\nExample:")
-		fmt.Printf("  go run %s data.xlsx\n", displayName)
+		fmt.Printf("  go run %s -o ndjson -z -split hashmod:0:8 data.xlsx\n", displayName)
 		fmt.Println("\nNote: The input file must be an Excel (.xlsx) file")
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
 		return
 	}
 
 	// Verify file exists and has correct extension
-	inputFile := os.Args[1]
+	inputFile := flag.Arg(0)
 	if !strings.HasSuffix(strings.ToLower(inputFile), ".xlsx") {
 		fmt.Printf("Error: Input file must be an Excel (.xlsx) file\n")
 		return
@@ -385,8 +890,22 @@ func main() {
 		return
 	}
 
+	// Cancel processing on Ctrl+C so in-flight writers flush and close their
+	// files instead of leaving truncated output; a checkpoint lets the next
+	// run resume from where this one was interrupted.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	go func() {
+		<-sigCh
+		fmt.Println("\n⚠️  Interrupt received, finishing in-flight rows and saving a checkpoint...")
+		cancel()
+	}()
+
 	fmt.Println("🚀 Starting data processing demonstration...")
-	demonstrateProcessingMethods(inputFile)
+	demonstrateProcessingMethods(ctx, inputFile, *outputFormat, *splitStrategy, *gzipOutput, *enableProfiling)
 }
 
 // go run main.go sequential_output.xlsx
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+)
+
+// startCPUProfile begins CPU profiling into <dir>/cpu_<method>.pprof and
+// returns a function that stops profiling and closes the file. Modeled on
+// the span-export CLI's -cpuprofile flag, but scoped per method so the
+// three processing methods can be compared individually.
+func startCPUProfile(method, dir string) (stop func(), err error) {
+	path := filepath.Join(dir, fmt.Sprintf("cpu_%s.pprof", method))
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pprof.StartCPUProfile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return func() {
+		pprof.StopCPUProfile()
+		file.Close()
+	}, nil
+}
+
+// writeHeapProfile snapshots the current heap into <dir>/heap_<method>.pprof.
+func writeHeapProfile(method, dir string) error {
+	path := filepath.Join(dir, fmt.Sprintf("heap_%s.pprof", method))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	runtime.GC() // get up-to-date statistics before the snapshot
+	return pprof.WriteHeapProfile(file)
+}
+
+// gcSnapshot captures the subset of runtime.MemStats that displayResults
+// reports on, so callers can diff a before/after pair into deltas.
+type gcSnapshot struct {
+	TotalAlloc   uint64
+	NumGC        uint32
+	PauseTotalNs uint64
+}
+
+func captureGCSnapshot() gcSnapshot {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return gcSnapshot{
+		TotalAlloc:   m.TotalAlloc,
+		NumGC:        m.NumGC,
+		PauseTotalNs: m.PauseTotalNs,
+	}
+}
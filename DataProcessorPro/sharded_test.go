@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// buildFixtureXLSX writes a workbook with a header row and n data rows under
+// dir, returning its path.
+func buildFixtureXLSX(t *testing.T, dir string, n int) string {
+	t.Helper()
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := f.GetSheetList()[0]
+	f.SetCellValue(sheet, "A1", "id")
+	f.SetCellValue(sheet, "B1", "value")
+	for i := 1; i <= n; i++ {
+		f.SetCellValue(sheet, fmt.Sprintf("A%d", i+1), i)
+		f.SetCellValue(sheet, fmt.Sprintf("B%d", i+1), fmt.Sprintf("row-%d", i))
+	}
+
+	path := filepath.Join(dir, "fixture.xlsx")
+	if err := f.SaveAs(path); err != nil {
+		t.Fatalf("writing fixture: %v", err)
+	}
+	return path
+}
+
+// sortedLineHash hashes path's lines after sorting them, so the comparison
+// doesn't depend on the order rows happened to arrive in.
+func sortedLineHash(t *testing.T, path string) string {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("opening %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte("\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// TestShardedMatchesSequential confirms processConcurrentSharded's merged
+// VA/VB output contains exactly the same rows as processSequential's for the
+// same input and Splitter. The comparison sorts each file's lines before
+// hashing: sharded workers race to read off a shared channel, so a worker's
+// shard preserves its own arrival order but the final merge doesn't
+// reconstruct the original row order the way the single sequential writer
+// does. That's an accepted trade-off of sharding for throughput — this test
+// only asserts that every row still lands on the same side, not in the same
+// position.
+func TestShardedMatchesSequential(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	const totalRows = 500
+	input := buildFixtureXLSX(t, dir, totalRows)
+
+	seqConfig := ProcessConfig{
+		Method:           "sequential",
+		BatchSize:        1,
+		NumWorkers:       1,
+		InputChannelCap:  64,
+		OutputChannelCap: 64,
+		FlushEvery:       500,
+		OutputFormat:     "csv",
+		Splitter:         NewHalfSplit(totalRows, 99),
+	}
+	shardedConfig := ProcessConfig{
+		Method:           "concurrent",
+		BatchSize:        1,
+		NumWorkers:       4,
+		InputChannelCap:  64,
+		OutputChannelCap: 64,
+		FlushEvery:       500,
+		OutputFormat:     "csv",
+		Splitter:         NewHalfSplit(totalRows, 99),
+		ShardedOutput:    true,
+	}
+
+	seqMetrics := &PerformanceMetrics{Method: seqConfig.Method}
+	processSequential(context.Background(), input, seqConfig, seqMetrics, 0, "")
+
+	shardMetrics := &PerformanceMetrics{Method: shardedConfig.Method}
+	processConcurrentSharded(context.Background(), input, shardedConfig, shardMetrics, 0, "")
+
+	if got, want := sortedLineHash(t, "VA_concurrent.csv"), sortedLineHash(t, "VA_sequential.csv"); got != want {
+		t.Errorf("VA_concurrent.csv sorted-line hash = %s, want %s (sequential)", got, want)
+	}
+	if got, want := sortedLineHash(t, "VB_concurrent.csv"), sortedLineHash(t, "VB_sequential.csv"); got != want {
+		t.Errorf("VB_concurrent.csv sorted-line hash = %s, want %s (sequential)", got, want)
+	}
+}
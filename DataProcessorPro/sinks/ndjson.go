@@ -0,0 +1,113 @@
+package sinks
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+)
+
+// ndjsonRow is the shape written for every classified row: the raw cell
+// values, keyed so the format stays self-describing without a fixed schema.
+type ndjsonRow struct {
+	Row []string `json:"row"`
+}
+
+// ndjsonSink writes one JSON object per row, newline-delimited.
+type ndjsonSink struct {
+	file   *os.File
+	writer *bufio.Writer
+	enc    *json.Encoder
+}
+
+// NewNDJSONSink creates a Sink that writes newline-delimited JSON.
+func NewNDJSONSink(path string) (Sink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(file)
+	return &ndjsonSink{file: file, writer: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+// NewNDJSONSinkAppend opens (or creates) path and writes new lines after
+// whatever it already contains, for resuming a checkpointed run.
+func NewNDJSONSinkAppend(path string) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(file)
+	return &ndjsonSink{file: file, writer: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+func (s *ndjsonSink) WriteRow(row []string) error {
+	return s.enc.Encode(ndjsonRow{Row: row})
+}
+
+func (s *ndjsonSink) Flush() error {
+	return s.writer.Flush()
+}
+
+func (s *ndjsonSink) Close() error {
+	if err := s.writer.Flush(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+// gzipNDJSONSink writes newline-delimited JSON through a gzip.Writer.
+type gzipNDJSONSink struct {
+	file *os.File
+	gz   *gzip.Writer
+	buf  *bufio.Writer
+	enc  *json.Encoder
+}
+
+// NewGzipNDJSONSink creates a Sink that writes gzip-compressed NDJSON.
+func NewGzipNDJSONSink(path string) (Sink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	buf := bufio.NewWriter(gz)
+	return &gzipNDJSONSink{file: file, gz: gz, buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+// NewGzipNDJSONSinkAppend opens (or creates) path and appends a new gzip
+// member containing the new NDJSON lines.
+func NewGzipNDJSONSinkAppend(path string) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(file)
+	buf := bufio.NewWriter(gz)
+	return &gzipNDJSONSink{file: file, gz: gz, buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+func (s *gzipNDJSONSink) WriteRow(row []string) error {
+	return s.enc.Encode(ndjsonRow{Row: row})
+}
+
+func (s *gzipNDJSONSink) Flush() error {
+	if err := s.buf.Flush(); err != nil {
+		return err
+	}
+	return s.gz.Flush()
+}
+
+func (s *gzipNDJSONSink) Close() error {
+	if err := s.buf.Flush(); err != nil {
+		s.gz.Close()
+		s.file.Close()
+		return err
+	}
+	if err := s.gz.Close(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
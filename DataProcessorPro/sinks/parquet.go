@@ -0,0 +1,67 @@
+package sinks
+
+import (
+	"encoding/json"
+
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetSchema declares a single repeated UTF8 column, since rows arrive as
+// untyped []string with no column names available from the source sheet.
+const parquetSchema = `{
+	"Tag": "name=row, repetitiontype=REQUIRED",
+	"Fields": [
+		{"Tag": "name=fields, type=LIST, valuetype=UTF8, repetitiontype=OPTIONAL"}
+	]
+}`
+
+// parquetRecord mirrors parquetSchema for the JSON-based writer.
+type parquetRecord struct {
+	Fields []string `json:"fields"`
+}
+
+// parquetSink writes rows into a columnar Parquet file. file is typed as the
+// source.ParquetFile interface rather than *local.LocalFile, since that's
+// what local.NewLocalFileWriter actually returns.
+type parquetSink struct {
+	file source.ParquetFile
+	pw   *writer.JSONWriter
+}
+
+// NewParquetSink creates a Sink backed by parquet-go's JSON writer.
+func NewParquetSink(path string) (Sink, error) {
+	file, err := local.NewLocalFileWriter(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pw, err := writer.NewJSONWriter(parquetSchema, file, 4)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &parquetSink{file: file, pw: pw}, nil
+}
+
+func (s *parquetSink) WriteRow(row []string) error {
+	rec, err := json.Marshal(parquetRecord{Fields: row})
+	if err != nil {
+		return err
+	}
+	return s.pw.Write(string(rec))
+}
+
+func (s *parquetSink) Flush() error {
+	return s.pw.Flush(true)
+}
+
+func (s *parquetSink) Close() error {
+	if err := s.pw.WriteStop(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
@@ -0,0 +1,113 @@
+// Package sinks provides pluggable output destinations for the rows that
+// DataProcessorPro classifies into VA/VB, so a single processing method can
+// be benchmarked against plain CSV, gzipped CSV, NDJSON, or Parquet output
+// without touching the classification code.
+package sinks
+
+import "fmt"
+
+// Sink is the minimal contract a processing method needs from an output
+// destination: write one classified row at a time, flush periodically, and
+// close cleanly when the method is done.
+type Sink interface {
+	WriteRow(row []string) error
+	Flush() error
+	Close() error
+}
+
+// constructor builds a Sink for a given file path.
+type constructor func(path string) (Sink, error)
+
+var registry = map[string]constructor{
+	"csv":     NewCSVSink,
+	"ndjson":  NewNDJSONSink,
+	"parquet": NewParquetSink,
+}
+
+// gzippedRegistry holds the gzip-compressed variant of each format that
+// supports one. Parquet is already a compressed columnar format, so it has
+// no gzip variant.
+var gzippedRegistry = map[string]constructor{
+	"csv":    NewGzipCSVSink,
+	"ndjson": NewGzipNDJSONSink,
+}
+
+// appendRegistry and gzippedAppendRegistry mirror registry/gzippedRegistry,
+// but open (or create) the file for appending rather than truncating it.
+// Parquet has no entry here: its footer/metadata can't be extended by
+// appending bytes, so resuming a parquet run starts that sink over.
+var appendRegistry = map[string]constructor{
+	"csv":    NewCSVSinkAppend,
+	"ndjson": NewNDJSONSinkAppend,
+}
+
+var gzippedAppendRegistry = map[string]constructor{
+	"csv":    NewGzipCSVSinkAppend,
+	"ndjson": NewGzipNDJSONSinkAppend,
+}
+
+// Open builds a Sink for the given format and path. If gzip is true, the
+// gzip-compressed variant of that format is used; formats without a gzip
+// variant return an error.
+func Open(format, path string, gzip bool) (Sink, error) {
+	reg := registry
+	if gzip {
+		reg = gzippedRegistry
+	}
+
+	ctor, ok := reg[format]
+	if !ok {
+		if gzip {
+			return nil, fmt.Errorf("sinks: no gzip variant registered for format %q", format)
+		}
+		return nil, fmt.Errorf("sinks: no sink registered for format %q", format)
+	}
+	return ctor(path)
+}
+
+// OpenAppend behaves like Open, but appends to an existing file instead of
+// truncating it, for resuming a previously checkpointed run. Formats that
+// cannot be safely extended by appending bytes (parquet) return an error.
+func OpenAppend(format, path string, gzip bool) (Sink, error) {
+	reg := appendRegistry
+	if gzip {
+		reg = gzippedAppendRegistry
+	}
+
+	ctor, ok := reg[format]
+	if !ok {
+		if gzip {
+			return nil, fmt.Errorf("sinks: no appendable gzip variant registered for format %q", format)
+		}
+		return nil, fmt.Errorf("sinks: no appendable sink registered for format %q", format)
+	}
+	return ctor(path)
+}
+
+// SupportsAppend reports whether OpenAppend has a registered constructor for
+// format/gzip, so a caller deciding whether to resume a checkpointed run can
+// find out up front that it can't (parquet) instead of discovering it only
+// after OpenAppend has already failed and the row stream has been drained.
+func SupportsAppend(format string, gzip bool) bool {
+	reg := appendRegistry
+	if gzip {
+		reg = gzippedAppendRegistry
+	}
+	_, ok := reg[format]
+	return ok
+}
+
+// Ext returns the conventional file extension for a format, including the
+// ".gz" suffix when gzip is requested.
+func Ext(format string, gzip bool) string {
+	ext := map[string]string{
+		"csv":     ".csv",
+		"ndjson":  ".ndjson",
+		"parquet": ".parquet",
+	}[format]
+
+	if gzip {
+		ext += ".gz"
+	}
+	return ext
+}
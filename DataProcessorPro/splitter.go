@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Splitter decides, for a given row, whether it belongs in VA (true) or VB
+// (false). Every processing method calls the same Splitter so that
+// sequential, concurrent, and batch runs classify rows identically given
+// the same configuration.
+type Splitter interface {
+	Assign(index int, row []string) bool
+
+	// OrderSensitive reports whether Assign's result depends on the sequence
+	// of previous calls (a shared PRNG stream, or running per-key counts)
+	// rather than being a pure function of index and row. Worker-pool
+	// methods must dispatch to an order-sensitive Splitter with exactly one
+	// worker, since a pool of goroutines pulling rows off a channel doesn't
+	// guarantee Assign is invoked in index order otherwise.
+	OrderSensitive() bool
+}
+
+// HalfSplit reproduces the tool's original behavior: the first half of rows
+// (by position) go to VA, the rest to VB, with the odd-row tiebreak decided
+// by a seeded PRNG so the boundary is reproducible.
+type HalfSplit struct {
+	boundary int
+}
+
+// NewHalfSplit builds a HalfSplit for a sheet with totalRows data rows,
+// seeded so repeated runs agree on the boundary.
+func NewHalfSplit(totalRows int, seed int64) *HalfSplit {
+	r := rand.New(rand.NewSource(seed))
+	half := totalRows / 2
+	if totalRows%2 != 0 && r.Float32() < 0.5 {
+		half++
+	}
+	return &HalfSplit{boundary: half}
+}
+
+func (h *HalfSplit) Assign(index int, row []string) bool {
+	return index < h.boundary
+}
+
+// OrderSensitive is always false: the boundary is fixed at construction, so
+// Assign can be called in any order and still agree with itself.
+func (h *HalfSplit) OrderSensitive() bool {
+	return false
+}
+
+// BernoulliSplit assigns each row to VA independently with probability p,
+// using a seeded PRNG guarded by a mutex since rows may be classified from
+// multiple goroutines concurrently.
+type BernoulliSplit struct {
+	p  float32
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// NewBernoulliSplit builds a BernoulliSplit with the given VA probability
+// and seed.
+func NewBernoulliSplit(p float32, seed int64) *BernoulliSplit {
+	return &BernoulliSplit{p: p, r: rand.New(rand.NewSource(seed))}
+}
+
+func (b *BernoulliSplit) Assign(index int, row []string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.r.Float32() < b.p
+}
+
+// OrderSensitive is always true: each call consumes the next value from a
+// shared PRNG stream, so two runs only agree on which rows land in VA if
+// Assign is called against them in the same order both times.
+func (b *BernoulliSplit) OrderSensitive() bool {
+	return true
+}
+
+// HashModSplit partitions rows by hashing a key column with FNV-1a and
+// bucketing into n buckets, so that every row sharing the same key always
+// lands on the same side regardless of which worker classifies it.
+type HashModSplit struct {
+	colIdx int
+	n      uint32
+}
+
+// NewHashModSplit builds a HashModSplit keyed on row[colIdx], hashed into n
+// buckets (even buckets go to VA, odd buckets go to VB).
+func NewHashModSplit(colIdx, n int) *HashModSplit {
+	return &HashModSplit{colIdx: colIdx, n: uint32(n)}
+}
+
+func (h *HashModSplit) Assign(index int, row []string) bool {
+	key := ""
+	if h.colIdx >= 0 && h.colIdx < len(row) {
+		key = row[h.colIdx]
+	}
+
+	hasher := fnv.New32a()
+	hasher.Write([]byte(key))
+	bucket := hasher.Sum32() % h.n
+	return bucket%2 == 0
+}
+
+// OrderSensitive is always false: the bucket depends only on row[colIdx], so
+// Assign can be called in any order and still agree with itself.
+func (h *HashModSplit) OrderSensitive() bool {
+	return false
+}
+
+// StratifiedSplit keeps the VA/VB ratio even within each distinct value of
+// the key column, by tracking a running VA/VB count per key and always
+// sending the next row to whichever side is currently behind for that key.
+type StratifiedSplit struct {
+	colIdx int
+	mu     sync.Mutex
+	counts map[string][2]int // [vaCount, vbCount] per key
+}
+
+// NewStratifiedSplit builds a StratifiedSplit keyed on row[colIdx].
+func NewStratifiedSplit(colIdx int) *StratifiedSplit {
+	return &StratifiedSplit{colIdx: colIdx, counts: make(map[string][2]int)}
+}
+
+func (s *StratifiedSplit) Assign(index int, row []string) bool {
+	key := ""
+	if s.colIdx >= 0 && s.colIdx < len(row) {
+		key = row[s.colIdx]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.counts[key]
+	toVA := c[0] <= c[1]
+	if toVA {
+		c[0]++
+	} else {
+		c[1]++
+	}
+	s.counts[key] = c
+	return toVA
+}
+
+// OrderSensitive is always true: each call updates a running per-key count
+// that later calls read, so two runs only agree on which rows land in VA if
+// Assign sees rows for each key in the same order both times.
+func (s *StratifiedSplit) OrderSensitive() bool {
+	return true
+}
+
+// NewSplitterFromSpec builds a fresh Splitter from a "-split" flag value.
+// It's a constructor, not a shared instance, so callers that need every
+// processing method to classify rows identically (see
+// demonstrateProcessingMethods) should call it once per method rather than
+// reusing the result, the same way NewHalfSplit was already used.
+//
+// Recognized specs:
+//
+//	half                   - NewHalfSplit(totalRows, seed)
+//	bernoulli:p[:seed]     - NewBernoulliSplit(p, seed)
+//	hashmod:col:n          - NewHashModSplit(col, n)
+//	stratified:col         - NewStratifiedSplit(col)
+func NewSplitterFromSpec(spec string, totalRows int, seed int64) (Splitter, error) {
+	parts := strings.Split(spec, ":")
+	switch parts[0] {
+	case "half", "":
+		return NewHalfSplit(totalRows, seed), nil
+
+	case "bernoulli":
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("split strategy %q requires a probability, e.g. bernoulli:0.5", spec)
+		}
+		p, err := strconv.ParseFloat(parts[1], 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bernoulli probability %q: %w", parts[1], err)
+		}
+		s := seed
+		if len(parts) >= 3 {
+			if s, err = strconv.ParseInt(parts[2], 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid bernoulli seed %q: %w", parts[2], err)
+			}
+		}
+		return NewBernoulliSplit(float32(p), s), nil
+
+	case "hashmod":
+		if len(parts) < 3 {
+			return nil, fmt.Errorf("split strategy %q requires a column index and bucket count, e.g. hashmod:0:8", spec)
+		}
+		col, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hashmod column %q: %w", parts[1], err)
+		}
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid hashmod bucket count %q: %w", parts[2], err)
+		}
+		if n <= 0 {
+			return nil, fmt.Errorf("hashmod bucket count must be positive, got %d", n)
+		}
+		return NewHashModSplit(col, n), nil
+
+	case "stratified":
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("split strategy %q requires a column index, e.g. stratified:0", spec)
+		}
+		col, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid stratified column %q: %w", parts[1], err)
+		}
+		return NewStratifiedSplit(col), nil
+
+	default:
+		return nil, fmt.Errorf("unknown split strategy %q", parts[0])
+	}
+}